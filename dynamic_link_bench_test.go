@@ -0,0 +1,155 @@
+package wasm
+
+import (
+	"encoding/base64"
+	"fmt"
+	"testing"
+
+	sdk "github.com/Finschia/finschia-sdk/types"
+	wasmvm "github.com/Finschia/wasmvm"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Finschia/wasmd/x/wasm/keeper"
+	"github.com/Finschia/wasmd/x/wasm/types"
+)
+
+// benchContractMemoryLimit must be kept equal to the keeper's unexported
+// contractMemoryLimit (in MiB), which it passes to wasmvm.NewVM.
+const benchContractMemoryLimit = 32
+
+type dynamicLinkBench struct {
+	data    testData
+	callee  sdk.AccAddress
+	caller  sdk.AccAddress
+	reflect sdk.AccAddress
+}
+
+// setupDynamicLinkBench stores and instantiates number.wasm, call_number.wasm and
+// the reflect contract on a VM using the given memory cache size.
+func setupDynamicLinkBench(b *testing.B, memoryCacheSize uint32) dynamicLinkBench {
+	b.Helper()
+	// replace the VM built by the keeper so that only the one with the requested cache stays alive
+	replaceVM := keeper.WithWasmEngineDecorator(func(old types.WasmerEngine) types.WasmerEngine {
+		old.Cleanup()
+		vm, err := wasmvm.NewVM(b.TempDir(), "iterator,staking,stargate,cosmwasm_1_1", benchContractMemoryLimit, false, memoryCacheSize)
+		require.NoError(b, err)
+		b.Cleanup(vm.Cleanup)
+		return vm
+	})
+	data := setupTest(b, replaceVM)
+
+	h := data.module.Route().Handler()
+	instantiate := func(codeID uint64, label, msg string) sdk.AccAddress {
+		res, err := h(data.ctx, &MsgInstantiateContract{
+			Sender: addr1,
+			CodeID: codeID,
+			Label:  label,
+			Msg:    []byte(msg),
+		})
+		require.NoError(b, err)
+		var resp types.MsgInstantiateContractResponse
+		require.NoError(b, resp.Unmarshal(res.Data))
+		return sdk.MustAccAddressFromBech32(resp.Address)
+	}
+
+	// store callee (number), caller (call-number) and reflect codes
+	for _, code := range [][]byte{numberContract, callNumberContract, maskContract} {
+		_, err := h(data.ctx, &MsgStoreCode{Sender: addr1, WASMByteCode: code})
+		require.NoError(b, err)
+	}
+
+	callee := instantiate(1, "number", `{"value":21}`)
+	return dynamicLinkBench{
+		data:    data,
+		callee:  callee,
+		caller:  instantiate(2, "call-number", fmt.Sprintf(`{"callee_addr":"%s"}`, callee)),
+		reflect: instantiate(3, "reflect", `{}`),
+	}
+}
+
+func (d dynamicLinkBench) execute(ctx sdk.Context, contract sdk.AccAddress, msg string) error {
+	_, err := d.data.module.Route().Handler()(ctx, &MsgExecuteContract{
+		Sender:   addr1,
+		Contract: contract.String(),
+		Msg:      []byte(msg),
+	})
+	return err
+}
+
+// BenchmarkDynamicCallOverhead compares reaching the number contract through a
+// dynamic call, a WasmQuery::Smart and a WasmMsg::Execute submessage, for
+// different VM memory cache sizes. The caller's mul issues both a dynamic call
+// and a smart query, so it is measured as one combined case; the read-only
+// cases isolate the dynamic call from the smart query. Besides ns/op the gas
+// consumed per operation is reported.
+func BenchmarkDynamicCallOverhead(b *testing.B) {
+	cacheSizes := []struct {
+		name string
+		size uint32
+	}{
+		{name: "no cache", size: 0},
+		{name: "default cache", size: DefaultWasmConfig().MemoryCacheSize},
+	}
+	mulMsg := base64.StdEncoding.EncodeToString([]byte(`{"mul":{"value":1}}`))
+	specs := []struct {
+		name string
+		run  func(ctx sdk.Context, d dynamicLinkBench) error
+	}{
+		{
+			name: "number via dynamic call",
+			run: func(ctx sdk.Context, d dynamicLinkBench) error {
+				_, err := d.data.keeper.QuerySmart(ctx, d.caller, []byte(`{"number_dyn":{}}`))
+				return err
+			},
+		},
+		{
+			name: "number via WasmQuery::Smart",
+			run: func(ctx sdk.Context, d dynamicLinkBench) error {
+				_, err := d.data.keeper.QuerySmart(ctx, d.caller, []byte(`{"number":{}}`))
+				return err
+			},
+		},
+		{
+			name: "mul via dynamic call and WasmQuery::Smart",
+			run: func(ctx sdk.Context, d dynamicLinkBench) error {
+				return d.execute(ctx, d.caller, `{"mul":{"value":1}}`)
+			},
+		},
+		{
+			name: "mul via WasmMsg::Execute",
+			run: func(ctx sdk.Context, d dynamicLinkBench) error {
+				return d.execute(ctx, d.reflect, fmt.Sprintf(`{"reflect_msg":{"msgs":[{"wasm":{"execute":{"contract_addr":"%s","msg":"%s","funds":[]}}}]}}`, d.callee, mulMsg))
+			},
+		},
+		{
+			name: "mul via direct execute",
+			run: func(ctx sdk.Context, d dynamicLinkBench) error {
+				return d.execute(ctx, d.callee, `{"mul":{"value":1}}`)
+			},
+		},
+	}
+	for _, cache := range cacheSizes {
+		for _, spec := range specs {
+			b.Run(fmt.Sprintf("%s/%s", cache.name, spec.name), func(b *testing.B) {
+				d := setupDynamicLinkBench(b, cache.size)
+				var gasUsed sdk.Gas
+
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					ctx := d.data.ctx.WithGasMeter(sdk.NewInfiniteGasMeter())
+					if err := spec.run(ctx, d); err != nil {
+						b.Fatal(err)
+					}
+					gasUsed += ctx.GasMeter().GasConsumed()
+				}
+				b.StopTimer()
+				b.ReportMetric(float64(gasUsed)/float64(b.N), "gas/op")
+
+				// multiplying by one must leave the callee's value untouched
+				res, err := d.data.keeper.QuerySmart(d.data.ctx, d.callee, []byte(`{"number":{}}`))
+				require.NoError(b, err)
+				require.Equal(b, []byte(`{"value":21}`), res)
+			})
+		}
+	}
+}